@@ -19,7 +19,6 @@ import (
 	"github.com/stackup-wallet/stackup-bundler/pkg/altmempools"
 	"github.com/stackup-wallet/stackup-bundler/pkg/bundler"
 	"github.com/stackup-wallet/stackup-bundler/pkg/client"
-	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/stake"
 	"github.com/stackup-wallet/stackup-bundler/pkg/gas"
 	"github.com/stackup-wallet/stackup-bundler/pkg/jsonrpc"
 	"github.com/stackup-wallet/stackup-bundler/pkg/mempool"
@@ -125,19 +124,14 @@ func SearcherMode() {
 
 	// Init Client
 	c := client.New(mem, ov, chain, conf.SupportedEntryPoints, conf.OpLookupLimit)
-	c.SetGetUserOpReceiptFunc(client.GetUserOpReceiptWithEthClient(eth))
-	c.SetGetGasPricesFunc(client.GetGasPricesWithEthClient(eth))
-	c.SetGetGasEstimateFunc(
-		client.GetGasEstimateWithEthClient(
-			rpc,
-			ov,
-			chain,
-			conf.MaxBatchGasLimit,
-			conf.NativeBundlerExecutorTracer,
-		),
-	)
-	c.SetGetUserOpByHashFunc(client.GetUserOpByHashWithEthClient(eth))
-	c.SetGetStakeFunc(stake.GetStakeWithEthClient(eth))
+	c.UseFuncs(client.NewFuncsWithEthClient(&client.FuncsConfig{
+		Rpc:                  rpc,
+		Ov:                   ov,
+		ChainID:              chain,
+		MaxGasLimit:          conf.MaxBatchGasLimit,
+		Tracer:               conf.NativeBundlerExecutorTracer,
+		SupportedEntryPoints: conf.SupportedEntryPoints,
+	}))
 	c.UseLogger(logr)
 	c.UseModules(
 		rep.CheckStatus(),
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stackup-wallet/stackup-bundler/pkg/state"
+	"github.com/stackup-wallet/stackup-bundler/pkg/userop"
+	"golang.org/x/sync/errgroup"
+)
+
+// GasEstimateResult holds the outcome of estimating gas for a single userOp in a batch.
+type GasEstimateResult struct {
+	VerificationGas uint64
+	CallGas         uint64
+	Err             error
+}
+
+// EstimateGasForOps concurrently estimates verificationGasLimit and callGasLimit for a batch of userOps with
+// at most the given number of workers. If workers is not positive, it defaults to runtime.NumCPU(). The
+// returned results are in the same order as ops and an error for one userOp does not affect the others.
+// Cancelling the context will abort all in-flight and remaining estimates.
+func EstimateGasForOps(
+	ctx context.Context,
+	fn GetGasEstimateFuncCtx,
+	ep common.Address,
+	ops []*userop.UserOperation,
+	sos state.OverrideSet,
+	workers int,
+) []GasEstimateResult {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	res := make([]GasEstimateResult, len(ops))
+	g := new(errgroup.Group)
+	g.SetLimit(workers)
+
+	for i, op := range ops {
+		i, op := i, op
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				res[i] = GasEstimateResult{Err: err}
+				return nil
+			}
+
+			vg, cg, err := fn(ctx, ep, op, sos)
+			res[i] = GasEstimateResult{VerificationGas: vg, CallGas: cg, Err: err}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return res
+}
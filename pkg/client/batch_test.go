@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stackup-wallet/stackup-bundler/internal/testutils"
+	"github.com/stackup-wallet/stackup-bundler/pkg/state"
+	"github.com/stackup-wallet/stackup-bundler/pkg/userop"
+)
+
+func mockOps(n int) []*userop.UserOperation {
+	ops := []*userop.UserOperation{}
+	for i := 0; i < n; i++ {
+		op := testutils.MockValidInitUserOp()
+		op.Nonce = big.NewInt(int64(i))
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// TestEstimateGasForOps verifies that results are returned in order and that an error for one userOp does not
+// affect the others.
+func TestEstimateGasForOps(t *testing.T) {
+	errBad := errors.New("AA23 reverted")
+	fn := func(
+		ctx context.Context,
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (uint64, uint64, error) {
+		if op.Nonce.Uint64() == 2 {
+			return 0, 0, errBad
+		}
+		return op.Nonce.Uint64() * 10, op.Nonce.Uint64() * 100, nil
+	}
+
+	res := EstimateGasForOps(context.Background(), fn, testutils.ValidAddress1, mockOps(5), nil, 2)
+	if len(res) != 5 {
+		t.Fatalf("got %d results, want 5", len(res))
+	}
+	for i, r := range res {
+		if i == 2 {
+			if !errors.Is(r.Err, errBad) {
+				t.Fatalf("result %d: got %v, want errBad", i, r.Err)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Fatalf("result %d: got %v, want nil", i, r.Err)
+		} else if r.VerificationGas != uint64(i*10) || r.CallGas != uint64(i*100) {
+			t.Fatalf("result %d: got %d, %d, want %d, %d", i, r.VerificationGas, r.CallGas, i*10, i*100)
+		}
+	}
+}
+
+func peakConcurrency(ops int, workers int) int32 {
+	var running, peak int32
+	fn := func(
+		ctx context.Context,
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (uint64, uint64, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return 0, 0, nil
+	}
+
+	_ = EstimateGasForOps(context.Background(), fn, testutils.ValidAddress1, mockOps(ops), nil, workers)
+	return atomic.LoadInt32(&peak)
+}
+
+// TestEstimateGasForOpsWorkers verifies that no more than the given number of estimates run at once.
+func TestEstimateGasForOpsWorkers(t *testing.T) {
+	if peak := peakConcurrency(8, 3); peak > 3 {
+		t.Fatalf("got peak concurrency %d, want <= 3", peak)
+	}
+}
+
+// TestEstimateGasForOpsDefaultWorkers verifies that a non-positive number of workers is bounded by the
+// number of CPUs.
+func TestEstimateGasForOpsDefaultWorkers(t *testing.T) {
+	max := int32(runtime.NumCPU())
+	for _, workers := range []int{0, -1} {
+		if peak := peakConcurrency(int(max)*2, workers); peak > max {
+			t.Fatalf("got peak concurrency %d, want <= %d", peak, max)
+		}
+	}
+}
+
+// TestEstimateGasForOpsCancelled verifies that a cancelled context returns an error for every userOp without
+// calling the estimate func.
+func TestEstimateGasForOpsCancelled(t *testing.T) {
+	calls := int32(0)
+	fn := func(
+		ctx context.Context,
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (uint64, uint64, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, 0, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i, r := range EstimateGasForOps(ctx, fn, testutils.ValidAddress1, mockOps(4), nil, 2) {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Fatalf("result %d: got %v, want context.Canceled", i, r.Err)
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("got %d calls, want 0", calls)
+	}
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+	"github.com/stackup-wallet/stackup-bundler/pkg/state"
+	"github.com/stackup-wallet/stackup-bundler/pkg/userop"
+)
+
+type gasEstimateEntry struct {
+	verificationGas uint64
+	callGas         uint64
+	expiresAt       time.Time
+}
+
+func getGasEstimateCacheKey(
+	ep common.Address,
+	op *userop.UserOperation,
+	sos state.OverrideSet,
+) (common.Hash, error) {
+	// Every userOp field except VGL and CGL is part of the key since those two are overwritten during
+	// estimation. Fees and PVG affect the prefund check and the signature is used during validation.
+	overrides, err := json.Marshal(sos)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(
+		ep.Bytes(),
+		op.Sender.Bytes(),
+		common.BigToHash(op.Nonce).Bytes(),
+		crypto.Keccak256(op.InitCode),
+		crypto.Keccak256(op.CallData),
+		common.BigToHash(op.PreVerificationGas).Bytes(),
+		common.BigToHash(op.MaxFeePerGas).Bytes(),
+		common.BigToHash(op.MaxPriorityFeePerGas).Bytes(),
+		crypto.Keccak256(op.PaymasterAndData),
+		crypto.Keccak256(op.Signature),
+		crypto.Keccak256(overrides),
+	), nil
+}
+
+// GetGasEstimateWithCache wraps an implementation of GetGasEstimateFunc with an in-memory LRU cache. Results
+// are keyed by the EntryPoint, every userOp field except verificationGasLimit and callGasLimit, and the state
+// OverrideSet.
+// Cached entries are only valid for the given TTL since the estimate depends on chain state. Errors are never
+// cached. A nil clock defaults to RealClock.
+func GetGasEstimateWithCache(
+	fn GetGasEstimateFunc,
+	size int,
+	ttl time.Duration,
+	clock Clock,
+) GetGasEstimateFunc {
+	clock = clockOrDefault(clock)
+	cache := lru.NewCache[common.Hash, gasEstimateEntry](size)
+
+	return func(
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (verificationGas uint64, callGas uint64, err error) {
+		key, err := getGasEstimateCacheKey(ep, op, sos)
+		if err != nil {
+			return 0, 0, err
+		}
+		if entry, ok := cache.Get(key); ok && clock.Now().Before(entry.expiresAt) {
+			return entry.verificationGas, entry.callGas, nil
+		}
+
+		vg, cg, err := fn(ep, op, sos)
+		if err != nil {
+			return 0, 0, err
+		}
+		cache.Add(key, gasEstimateEntry{
+			verificationGas: vg,
+			callGas:         cg,
+			expiresAt:       clock.Now().Add(ttl),
+		})
+		return vg, cg, nil
+	}
+}
+
+type userOpReceiptKey struct {
+	hash     string
+	ep       common.Address
+	blkRange uint64
+}
+
+type userOpReceiptEntry struct {
+	receipt   *filter.UserOperationReceipt
+	final     bool
+	expiresAt time.Time
+}
+
+func isFinalReceipt(eth *ethclient.Client, receipt *filter.UserOperationReceipt, depth uint64) (bool, error) {
+	if receipt.Receipt == nil {
+		return false, nil
+	}
+	blk, err := hexutil.DecodeUint64(receipt.Receipt.BlockNumber)
+	if err != nil {
+		return false, err
+	}
+	head, err := eth.BlockNumber(context.Background())
+	if err != nil {
+		return false, err
+	}
+	return head >= blk+depth, nil
+}
+
+// GetUserOpReceiptWithCache wraps an implementation of GetUserOpReceiptFunc with an in-memory LRU cache keyed
+// by userOpHash, EntryPoint, and block range. A receipt is held until evicted once its block is at least
+// finalityDepth blocks below the head of the chain. Any other result, including no receipt, is only cached
+// for ttl since the userOp may still be included in a later block or the receipt may change after a reorg.
+// Errors from fn are never cached. A nil clock defaults to RealClock.
+func GetUserOpReceiptWithCache(
+	fn GetUserOpReceiptFunc,
+	eth *ethclient.Client,
+	finalityDepth uint64,
+	size int,
+	ttl time.Duration,
+	clock Clock,
+) GetUserOpReceiptFunc {
+	mustHaveEthClient("GetUserOpReceiptWithCache", eth)
+	if finalityDepth == 0 {
+		panic("client: GetUserOpReceiptWithCache requires finalityDepth greater than 0")
+	}
+	clock = clockOrDefault(clock)
+	cache := lru.NewCache[userOpReceiptKey, userOpReceiptEntry](size)
+
+	return func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
+		key := userOpReceiptKey{strings.ToLower(hash), ep, blkRange}
+		if entry, ok := cache.Get(key); ok {
+			if entry.final || clock.Now().Before(entry.expiresAt) {
+				return entry.receipt, nil
+			}
+		}
+
+		receipt, err := fn(hash, ep, blkRange)
+		if err != nil {
+			return nil, err
+		}
+		// If finality cannot be checked, fallback to caching the receipt for ttl only.
+		final := false
+		if receipt != nil {
+			final, _ = isFinalReceipt(eth, receipt, finalityDepth)
+		}
+		cache.Add(key, userOpReceiptEntry{
+			receipt:   receipt,
+			final:     final,
+			expiresAt: clock.Now().Add(ttl),
+		})
+		return receipt, nil
+	}
+}
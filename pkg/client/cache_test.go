@@ -0,0 +1,234 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stackup-wallet/stackup-bundler/internal/testutils"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+	"github.com/stackup-wallet/stackup-bundler/pkg/state"
+	"github.com/stackup-wallet/stackup-bundler/pkg/userop"
+)
+
+func countingGasEstimate(calls *int, err error) GetGasEstimateFunc {
+	return func(
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (verificationGas uint64, callGas uint64, e error) {
+		*calls++
+		if err != nil {
+			return 0, 0, err
+		}
+		return 100000, 50000, nil
+	}
+}
+
+// TestGetGasEstimateWithCacheHit verifies that a repeated estimate for the same userOp is served from the
+// cache.
+func TestGetGasEstimateWithCacheHit(t *testing.T) {
+	calls := 0
+	fn := GetGasEstimateWithCache(countingGasEstimate(&calls, nil), 10, time.Minute, nil)
+	op := testutils.MockValidInitUserOp()
+
+	for i := 0; i < 3; i++ {
+		vg, cg, err := fn(testutils.ValidAddress1, op, nil)
+		if err != nil {
+			t.Fatalf("got %v, want nil", err)
+		} else if vg != 100000 || cg != 50000 {
+			t.Fatalf("got %d, %d, want 100000, 50000", vg, cg)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+}
+
+// TestGetGasEstimateWithCacheKey verifies that changes to any userOp field other than VGL and CGL or to the
+// override set result in a cache miss.
+func TestGetGasEstimateWithCacheKey(t *testing.T) {
+	calls := 0
+	fn := GetGasEstimateWithCache(countingGasEstimate(&calls, nil), 10, time.Minute, nil)
+	op1 := testutils.MockValidInitUserOp()
+	op2 := testutils.MockValidInitUserOp()
+	op2.Nonce = big.NewInt(1)
+	op3 := testutils.MockValidInitUserOp()
+	op3.Signature = common.Hex2Bytes("ff")
+	op4 := testutils.MockValidInitUserOp()
+	op4.PreVerificationGas = big.NewInt(0).Add(op1.PreVerificationGas, common.Big1)
+	sos := state.OverrideSet{testutils.ValidAddress2: state.OverrideAccount{}}
+
+	_, _, _ = fn(testutils.ValidAddress1, op1, nil)
+	_, _, _ = fn(testutils.ValidAddress1, op2, nil)
+	_, _, _ = fn(testutils.ValidAddress1, op3, nil)
+	_, _, _ = fn(testutils.ValidAddress1, op4, nil)
+	_, _, _ = fn(testutils.ValidAddress2, op1, nil)
+	_, _, _ = fn(testutils.ValidAddress1, op1, sos)
+	if calls != 6 {
+		t.Fatalf("got %d calls, want 6", calls)
+	}
+
+	// VGL and CGL are overwritten during estimation so they should not cause a miss.
+	op5 := testutils.MockValidInitUserOp()
+	op5.VerificationGasLimit = big.NewInt(1)
+	op5.CallGasLimit = big.NewInt(1)
+	_, _, _ = fn(testutils.ValidAddress1, op5, nil)
+	if calls != 6 {
+		t.Fatalf("got %d calls, want 6", calls)
+	}
+}
+
+// TestGetGasEstimateWithCacheExpired verifies that entries are not served after the TTL has passed.
+func TestGetGasEstimateWithCacheExpired(t *testing.T) {
+	calls := 0
+	clock := newFakeClock()
+	fn := GetGasEstimateWithCache(countingGasEstimate(&calls, nil), 10, time.Minute, clock)
+	op := testutils.MockValidInitUserOp()
+
+	_, _, _ = fn(testutils.ValidAddress1, op, nil)
+	clock.Advance(59 * time.Second)
+	_, _, _ = fn(testutils.ValidAddress1, op, nil)
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+
+	clock.Advance(time.Second)
+	_, _, _ = fn(testutils.ValidAddress1, op, nil)
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2", calls)
+	}
+}
+
+// TestGetGasEstimateWithCacheError verifies that errors are not cached.
+func TestGetGasEstimateWithCacheError(t *testing.T) {
+	calls := 0
+	fn := GetGasEstimateWithCache(
+		countingGasEstimate(&calls, errors.New("AA23 reverted")),
+		10,
+		time.Minute,
+		nil,
+	)
+	op := testutils.MockValidInitUserOp()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := fn(testutils.ValidAddress1, op, nil); err == nil {
+			t.Fatal("got nil, want err")
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2", calls)
+	}
+}
+
+func countingUserOpReceipt(calls *int, receipt *filter.UserOperationReceipt) GetUserOpReceiptFunc {
+	return func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
+		*calls++
+		return receipt, nil
+	}
+}
+
+func headEthClient(t *testing.T, head string) *ethclient.Client {
+	srv := testutils.RpcMock(testutils.MethodMocks{"eth_blockNumber": head})
+	t.Cleanup(srv.Close)
+	c, err := rpc.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ethclient.NewClient(c)
+}
+
+func mockReceiptInBlock(t *testing.T, blk string) *filter.UserOperationReceipt {
+	receipt := &filter.UserOperationReceipt{}
+	data := fmt.Sprintf(`{"userOpHash":%q,"receipt":{"blockNumber":%q}}`, testutils.MockHash, blk)
+	if err := json.Unmarshal([]byte(data), receipt); err != nil {
+		t.Fatal(err)
+	}
+	return receipt
+}
+
+// TestGetUserOpReceiptWithCacheFinal verifies that a receipt with a finalized block is served from the cache
+// after the TTL has passed.
+func TestGetUserOpReceiptWithCacheFinal(t *testing.T) {
+	calls := 0
+	clock := newFakeClock()
+	receipt := mockReceiptInBlock(t, "0x5a")
+	eth := headEthClient(t, "0x64")
+	fn := GetUserOpReceiptWithCache(countingUserOpReceipt(&calls, receipt), eth, 5, 10, time.Minute, clock)
+
+	for i := 0; i < 2; i++ {
+		if r, err := fn(testutils.MockHash, testutils.ValidAddress1, 2000); err != nil {
+			t.Fatalf("got %v, want nil", err)
+		} else if r != receipt {
+			t.Fatalf("got %v, want %v", r, receipt)
+		}
+		clock.Advance(time.Hour)
+	}
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+}
+
+// TestGetUserOpReceiptWithCacheNotFinal verifies that a receipt in a block that is not yet finalized is only
+// cached for the TTL.
+func TestGetUserOpReceiptWithCacheNotFinal(t *testing.T) {
+	calls := 0
+	clock := newFakeClock()
+	receipt := mockReceiptInBlock(t, "0x62")
+	eth := headEthClient(t, "0x64")
+	fn := GetUserOpReceiptWithCache(countingUserOpReceipt(&calls, receipt), eth, 5, 10, time.Minute, clock)
+
+	_, _ = fn(testutils.MockHash, testutils.ValidAddress1, 2000)
+	clock.Advance(59 * time.Second)
+	_, _ = fn(testutils.MockHash, testutils.ValidAddress1, 2000)
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+
+	clock.Advance(time.Second)
+	_, _ = fn(testutils.MockHash, testutils.ValidAddress1, 2000)
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2", calls)
+	}
+}
+
+// TestGetUserOpReceiptWithCacheNotFound verifies that a missing receipt is only cached for the TTL and is
+// not served to a lookup with a different block range.
+func TestGetUserOpReceiptWithCacheNotFound(t *testing.T) {
+	calls := 0
+	clock := newFakeClock()
+	eth := headEthClient(t, "0x64")
+	fn := GetUserOpReceiptWithCache(countingUserOpReceipt(&calls, nil), eth, 5, 10, time.Minute, clock)
+
+	_, _ = fn(testutils.MockHash, testutils.ValidAddress1, 2000)
+	_, _ = fn(testutils.MockHash, testutils.ValidAddress1, 2000)
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+	_, _ = fn(testutils.MockHash, testutils.ValidAddress1, 4000)
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2", calls)
+	}
+
+	clock.Advance(time.Minute)
+	_, _ = fn(testutils.MockHash, testutils.ValidAddress1, 2000)
+	if calls != 3 {
+		t.Fatalf("got %d calls, want 3", calls)
+	}
+}
+
+// TestGetUserOpReceiptWithCacheZeroDepth verifies that a finality depth of 0 is rejected since it would cache
+// receipts in the head block indefinitely.
+func TestGetUserOpReceiptWithCacheZeroDepth(t *testing.T) {
+	calls := 0
+	eth := headEthClient(t, "0x64")
+	expectPanic(t, "GetUserOpReceiptWithCache", func() {
+		GetUserOpReceiptWithCache(countingUserOpReceipt(&calls, nil), eth, 0, 10, time.Minute, nil)
+	})
+}
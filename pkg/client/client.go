@@ -2,7 +2,6 @@
 package client
 
 import (
-	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -68,7 +67,7 @@ func (i *Client) parseEntryPointAddress(ep string) (common.Address, error) {
 		}
 	}
 
-	return common.Address{}, errors.New("entryPoint: Implementation not supported")
+	return common.Address{}, ErrUnsupportedEntryPoint
 }
 
 // UseLogger defines the logger object used by the Client instance based on the go-logr/logr interface.
@@ -113,6 +112,26 @@ func (i *Client) SetGetStakeFunc(fn stake.GetStakeFunc) {
 	i.getStakeFunc = fn
 }
 
+// UseFuncs defines all general functions used by the Client from a single Funcs object. Any nil field will be
+// ignored and the existing function will remain in place.
+func (i *Client) UseFuncs(f *Funcs) {
+	if f.GetUserOpReceipt != nil {
+		i.SetGetUserOpReceiptFunc(f.GetUserOpReceipt)
+	}
+	if f.GetGasPrices != nil {
+		i.SetGetGasPricesFunc(f.GetGasPrices)
+	}
+	if f.GetGasEstimate != nil {
+		i.SetGetGasEstimateFunc(f.GetGasEstimate)
+	}
+	if f.GetUserOpByHash != nil {
+		i.SetGetUserOpByHashFunc(f.GetUserOpByHash)
+	}
+	if f.GetStake != nil {
+		i.SetGetStakeFunc(f.GetStake)
+	}
+}
+
 // SendUserOperation implements the method call for eth_sendUserOperation.
 // It returns true if userOp was accepted otherwise returns an error.
 func (i *Client) SendUserOperation(op map[string]any, ep string) (string, error) {
@@ -272,6 +291,28 @@ func (i *Client) GetUserOperationByHash(hash string) (*filter.HashLookupResult,
 	return res, nil
 }
 
+// GetUserOperationDetails returns both the UserOperation and its receipt for a given userOpHash returned by
+// *Client.SendUserOperation.
+func (i *Client) GetUserOperationDetails(hash string) (*UserOpDetails, error) {
+	// Init logger
+	l := i.logger.WithName("getUserOperationDetails").WithValues("userop_hash", hash)
+
+	res, err := GetUserOpDetails(
+		i.getUserOpByHash,
+		i.getUserOpReceipt,
+		hash,
+		i.supportedEntryPoints[0],
+		i.chainID,
+		i.opLookupLimit,
+	)
+	if err != nil {
+		l.Error(err, "getUserOperationDetails error")
+		return nil, err
+	}
+
+	return res, nil
+}
+
 // SupportedEntryPoints implements the method call for eth_supportedEntryPoints. It returns the array of
 // EntryPoint addresses that is supported by the client. The first address in the array is the preferred
 // EntryPoint.
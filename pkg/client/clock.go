@@ -0,0 +1,33 @@
+package client
+
+import "time"
+
+// Clock is a general interface for reading the current time and waiting for a duration. Time-sensitive
+// code in the client, such as cache TTLs and retry backoff, relies on it so that tests can control time
+// without real sleeps.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// RealClock returns a Clock that relies on the time package.
+func RealClock() Clock {
+	return realClock{}
+}
+
+func clockOrDefault(clock Clock) Clock {
+	if clock == nil {
+		return RealClock()
+	}
+	return clock
+}
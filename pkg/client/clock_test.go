@@ -0,0 +1,49 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a Clock where time only moves forward when Advance is called or when After is waited on.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	waited []time.Duration
+
+	// onAfter is called with the number of waits so far each time After is called.
+	onAfter func(n int)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.waited = append(c.waited, d)
+	n, now, onAfter := len(c.waited), c.now, c.onAfter
+	c.mu.Unlock()
+
+	if onAfter != nil {
+		onAfter(n)
+	}
+	ch := make(chan time.Time, 1)
+	ch <- now
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"errors"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+	"github.com/stackup-wallet/stackup-bundler/pkg/state"
+	"github.com/stackup-wallet/stackup-bundler/pkg/userop"
+)
+
+// ErrUnsupportedEntryPoint is returned when a func is called with an EntryPoint that is not in the registry.
+var ErrUnsupportedEntryPoint = errors.New("entryPoint: Implementation not supported")
+
+// EntryPointRegistry is a set of EntryPoint addresses that are allowed to be passed to the client funcs.
+// It is safe for concurrent use so that new EntryPoint versions can be added at runtime.
+type EntryPointRegistry struct {
+	eps mapset.Set[common.Address]
+}
+
+// NewEntryPointRegistry returns an EntryPointRegistry that allows the given EntryPoint addresses.
+func NewEntryPointRegistry(eps ...common.Address) *EntryPointRegistry {
+	return &EntryPointRegistry{eps: mapset.NewSet(eps...)}
+}
+
+// Add allows an EntryPoint address to be used with the wrapped funcs.
+func (r *EntryPointRegistry) Add(ep common.Address) {
+	r.eps.Add(ep)
+}
+
+// Remove disallows an EntryPoint address from being used with the wrapped funcs.
+func (r *EntryPointRegistry) Remove(ep common.Address) {
+	r.eps.Remove(ep)
+}
+
+// IsSupported returns true if the EntryPoint address is in the registry.
+func (r *EntryPointRegistry) IsSupported(ep common.Address) bool {
+	return r.eps.Contains(ep)
+}
+
+// WithGasEstimateFunc wraps an implementation of GetGasEstimateFunc so that calls with an unsupported
+// EntryPoint return ErrUnsupportedEntryPoint without reaching the node.
+func (r *EntryPointRegistry) WithGasEstimateFunc(fn GetGasEstimateFunc) GetGasEstimateFunc {
+	return func(
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (verificationGas uint64, callGas uint64, err error) {
+		if !r.IsSupported(ep) {
+			return 0, 0, ErrUnsupportedEntryPoint
+		}
+		return fn(ep, op, sos)
+	}
+}
+
+// WithUserOpReceiptFunc wraps an implementation of GetUserOpReceiptFunc so that calls with an unsupported
+// EntryPoint return ErrUnsupportedEntryPoint without reaching the node.
+func (r *EntryPointRegistry) WithUserOpReceiptFunc(fn GetUserOpReceiptFunc) GetUserOpReceiptFunc {
+	return func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
+		if !r.IsSupported(ep) {
+			return nil, ErrUnsupportedEntryPoint
+		}
+		return fn(hash, ep, blkRange)
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stackup-wallet/stackup-bundler/internal/testutils"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+)
+
+// TestEntryPointRegistryGasEstimate verifies that gas estimates for an unsupported EntryPoint are rejected
+// before calling the underlying func.
+func TestEntryPointRegistryGasEstimate(t *testing.T) {
+	calls := 0
+	r := NewEntryPointRegistry(testutils.ValidAddress1)
+	fn := r.WithGasEstimateFunc(countingGasEstimate(&calls, nil))
+	op := testutils.MockValidInitUserOp()
+
+	if _, _, err := fn(testutils.ValidAddress1, op, nil); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+	if _, _, err := fn(testutils.ValidAddress2, op, nil); !errors.Is(err, ErrUnsupportedEntryPoint) {
+		t.Fatalf("got %v, want ErrUnsupportedEntryPoint", err)
+	}
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+
+	r.Add(testutils.ValidAddress2)
+	if _, _, err := fn(testutils.ValidAddress2, op, nil); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+}
+
+// TestEntryPointRegistryUserOpReceipt verifies that receipt lookups for an unsupported EntryPoint are
+// rejected before calling the underlying func.
+func TestEntryPointRegistryUserOpReceipt(t *testing.T) {
+	calls := 0
+	r := NewEntryPointRegistry(testutils.ValidAddress1, testutils.ValidAddress2)
+	fn := r.WithUserOpReceiptFunc(countingUserOpReceipt(&calls, &filter.UserOperationReceipt{}))
+
+	r.Remove(testutils.ValidAddress2)
+	for _, ep := range []common.Address{testutils.ValidAddress2, common.HexToAddress("0x")} {
+		if _, err := fn(testutils.MockHash, ep, 2000); !errors.Is(err, ErrUnsupportedEntryPoint) {
+			t.Fatalf("%s: got %v, want ErrUnsupportedEntryPoint", ep, err)
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("got %d calls, want 0", calls)
+	}
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/stake"
+	"github.com/stackup-wallet/stackup-bundler/pkg/gas"
+)
+
+// Funcs bundles the general functions used by a Client to interact with the network. Each field is typed so
+// that a change to any implementation's signature is caught at compile time.
+type Funcs struct {
+	GetUserOpReceipt GetUserOpReceiptFunc
+	GetGasPrices     GetGasPricesFunc
+	GetGasEstimate   GetGasEstimateFunc
+	GetUserOpByHash  GetUserOpByHashFunc
+	GetStake         stake.GetStakeFunc
+}
+
+// FuncsConfig contains the shared parameters required to create Funcs from a single RPC client.
+type FuncsConfig struct {
+	Rpc                  *rpc.Client
+	Ov                   *gas.Overhead
+	ChainID              *big.Int
+	MaxGasLimit          *big.Int
+	Tracer               string
+	SupportedEntryPoints []common.Address
+}
+
+// NewFuncsWithEthClient returns Funcs where every field is set to the eth client based implementation. Gas
+// estimates and receipt lookups for an EntryPoint that is not in SupportedEntryPoints will return
+// ErrUnsupportedEntryPoint without reaching the node.
+func NewFuncsWithEthClient(cfg *FuncsConfig) *Funcs {
+	if len(cfg.SupportedEntryPoints) == 0 {
+		panic("client: NewFuncsWithEthClient requires at least one supported EntryPoint")
+	}
+	eth := ethclient.NewClient(cfg.Rpc)
+	eps := NewEntryPointRegistry(cfg.SupportedEntryPoints...)
+
+	return &Funcs{
+		GetUserOpReceipt: eps.WithUserOpReceiptFunc(GetUserOpReceiptWithEthClient(eth)),
+		GetGasPrices:     GetGasPricesWithEthClient(eth),
+		GetGasEstimate: eps.WithGasEstimateFunc(GetGasEstimateWithEthClient(
+			cfg.Rpc,
+			cfg.Ov,
+			cfg.ChainID,
+			cfg.MaxGasLimit,
+			cfg.Tracer,
+		)),
+		GetUserOpByHash: GetUserOpByHashWithEthClient(eth),
+		GetStake:        stake.GetStakeWithEthClient(eth),
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stackup-wallet/stackup-bundler/internal/testutils"
+	"github.com/stackup-wallet/stackup-bundler/pkg/fees"
+	"github.com/stackup-wallet/stackup-bundler/pkg/gas"
+)
+
+func mockFuncsConfig(t *testing.T) *FuncsConfig {
+	srv := testutils.RpcMock(testutils.MethodMocks{})
+	t.Cleanup(srv.Close)
+	c, err := rpc.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &FuncsConfig{
+		Rpc:                  c,
+		Ov:                   gas.NewDefaultOverhead(),
+		ChainID:              testutils.ChainID,
+		MaxGasLimit:          big.NewInt(30000000),
+		SupportedEntryPoints: []common.Address{testutils.ValidAddress1},
+	}
+}
+
+// TestNewFuncsWithEthClient verifies that every field is set from a single config.
+func TestNewFuncsWithEthClient(t *testing.T) {
+	f := NewFuncsWithEthClient(mockFuncsConfig(t))
+	if f.GetUserOpReceipt == nil ||
+		f.GetGasPrices == nil ||
+		f.GetGasEstimate == nil ||
+		f.GetUserOpByHash == nil ||
+		f.GetStake == nil {
+		t.Fatalf("got %+v, want all fields set", f)
+	}
+}
+
+// TestNewFuncsWithEthClientUnsupportedEntryPoint verifies that gas estimates and receipt lookups reject an
+// EntryPoint that is not in the config.
+func TestNewFuncsWithEthClientUnsupportedEntryPoint(t *testing.T) {
+	f := NewFuncsWithEthClient(mockFuncsConfig(t))
+
+	op := testutils.MockValidInitUserOp()
+	if _, _, err := f.GetGasEstimate(testutils.ValidAddress2, op, nil); !errors.Is(
+		err,
+		ErrUnsupportedEntryPoint,
+	) {
+		t.Fatalf("got %v, want ErrUnsupportedEntryPoint", err)
+	}
+	if _, err := f.GetUserOpReceipt(testutils.MockHash, testutils.ValidAddress2, 2000); !errors.Is(
+		err,
+		ErrUnsupportedEntryPoint,
+	) {
+		t.Fatalf("got %v, want ErrUnsupportedEntryPoint", err)
+	}
+
+	cfg := mockFuncsConfig(t)
+	cfg.SupportedEntryPoints = nil
+	expectPanic(t, "NewFuncsWithEthClient", func() { NewFuncsWithEthClient(cfg) })
+}
+
+// TestUseFuncsIgnoresNil verifies that nil fields do not override the existing functions on the Client.
+func TestUseFuncsIgnoresNil(t *testing.T) {
+	c := &Client{getGasPrices: getGasPricesNoop(), getGasEstimate: getGasEstimateNoop()}
+
+	called := false
+	c.UseFuncs(&Funcs{
+		GetGasPrices: func() (*fees.GasPrices, error) {
+			called = true
+			return nil, nil
+		},
+	})
+
+	if c.getGasEstimate == nil {
+		t.Fatal("got nil getGasEstimate, want noop")
+	}
+	if _, err := c.getGasPrices(); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if !called {
+		t.Fatal("got noop getGasPrices, want override")
+	}
+}
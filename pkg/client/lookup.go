@@ -0,0 +1,48 @@
+package client
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+	"golang.org/x/sync/errgroup"
+)
+
+// UserOpDetails holds the combined result of looking up a userOp and its receipt by userOpHash.
+type UserOpDetails struct {
+	Op      *filter.HashLookupResult
+	Receipt *filter.UserOperationReceipt
+
+	// Pending is true if the userOp was found but does not have a receipt yet.
+	Pending bool
+}
+
+// GetUserOpDetails concurrently fetches a userOp and its receipt for the given userOpHash using the same
+// block range for both lookups. An error from either lookup is returned.
+func GetUserOpDetails(
+	getUserOpByHash GetUserOpByHashFunc,
+	getUserOpReceipt GetUserOpReceiptFunc,
+	hash string,
+	ep common.Address,
+	chain *big.Int,
+	blkRange uint64,
+) (*UserOpDetails, error) {
+	res := &UserOpDetails{}
+	g := new(errgroup.Group)
+	g.Go(func() error {
+		op, err := getUserOpByHash(hash, ep, chain, blkRange)
+		res.Op = op
+		return err
+	})
+	g.Go(func() error {
+		receipt, err := getUserOpReceipt(hash, ep, blkRange)
+		res.Receipt = receipt
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	res.Pending = res.Op != nil && res.Receipt == nil
+	return res, nil
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stackup-wallet/stackup-bundler/internal/testutils"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+)
+
+func staticUserOpByHash(res *filter.HashLookupResult, err error) GetUserOpByHashFunc {
+	return func(hash string, ep common.Address, chain *big.Int, blkRange uint64) (*filter.HashLookupResult, error) {
+		return res, err
+	}
+}
+
+func staticUserOpReceipt(res *filter.UserOperationReceipt, err error) GetUserOpReceiptFunc {
+	return func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
+		return res, err
+	}
+}
+
+// TestGetUserOpDetailsPending verifies that the pending flag is only set when the userOp is found without a
+// receipt.
+func TestGetUserOpDetailsPending(t *testing.T) {
+	op := &filter.HashLookupResult{UserOperation: testutils.MockValidInitUserOp()}
+	receipt := &filter.UserOperationReceipt{UserOpHash: common.HexToHash(testutils.MockHash)}
+
+	cases := []struct {
+		op      *filter.HashLookupResult
+		receipt *filter.UserOperationReceipt
+		pending bool
+	}{
+		{op, receipt, false},
+		{op, nil, true},
+		{nil, nil, false},
+	}
+	for _, c := range cases {
+		res, err := GetUserOpDetails(
+			staticUserOpByHash(c.op, nil),
+			staticUserOpReceipt(c.receipt, nil),
+			testutils.MockHash,
+			testutils.ValidAddress1,
+			testutils.ChainID,
+			2000,
+		)
+		if err != nil {
+			t.Fatalf("got %v, want nil", err)
+		} else if res.Op != c.op || res.Receipt != c.receipt {
+			t.Fatalf("got %v, %v, want %v, %v", res.Op, res.Receipt, c.op, c.receipt)
+		} else if res.Pending != c.pending {
+			t.Fatalf("got pending %t, want %t", res.Pending, c.pending)
+		}
+	}
+}
+
+// TestGetUserOpDetailsError verifies that an error from either lookup is returned.
+func TestGetUserOpDetailsError(t *testing.T) {
+	lookupErr := errors.New("connection refused")
+	if _, err := GetUserOpDetails(
+		staticUserOpByHash(nil, nil),
+		staticUserOpReceipt(nil, lookupErr),
+		testutils.MockHash,
+		testutils.ValidAddress1,
+		testutils.ChainID,
+		2000,
+	); !errors.Is(err, lookupErr) {
+		t.Fatalf("got %v, want %v", err, lookupErr)
+	}
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+	"github.com/stackup-wallet/stackup-bundler/pkg/gas"
+	"github.com/stackup-wallet/stackup-bundler/pkg/state"
+	"github.com/stackup-wallet/stackup-bundler/pkg/userop"
+)
+
+func mustHaveReconnectingEthClient(constructor string, r *ReconnectingEthClient) {
+	if r == nil {
+		panic(fmt.Sprintf("client: %s requires a non-nil reconnecting eth client", constructor))
+	}
+}
+
+const (
+	defaultReconnectDialTimeout = 10 * time.Second
+	maxReconnectBackoff         = time.Minute
+)
+
+// ReconnectingEthClient holds an RPC client for a single endpoint URL. When a call fails with a
+// connection-level error, the endpoint is re-dialed with exponential backoff and the call is retried so that
+// the funcs built on top of it can recover from a node restart.
+type ReconnectingEthClient struct {
+	url         string
+	maxRetries  int
+	backoff     time.Duration
+	dialTimeout time.Duration
+	clock       Clock
+
+	mu  sync.RWMutex
+	rpc *rpc.Client
+}
+
+// NewReconnectingEthClient dials the endpoint at url and returns a ReconnectingEthClient. On a
+// connection-level error, a call is retried up to maxRetries times with the delay starting at backoff and
+// doubling after each attempt, up to a maximum of one minute. An error is returned if maxRetries is negative
+// or backoff is not positive.
+func NewReconnectingEthClient(
+	url string,
+	maxRetries int,
+	backoff time.Duration,
+) (*ReconnectingEthClient, error) {
+	if maxRetries < 0 {
+		return nil, fmt.Errorf("reconnect: maxRetries must not be negative, got %d", maxRetries)
+	}
+	if backoff <= 0 {
+		return nil, fmt.Errorf("reconnect: backoff must be greater than 0, got %s", backoff)
+	}
+
+	c, err := rpc.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReconnectingEthClient{
+		url:         url,
+		maxRetries:  maxRetries,
+		backoff:     backoff,
+		dialTimeout: defaultReconnectDialTimeout,
+		clock:       RealClock(),
+		rpc:         c,
+	}, nil
+}
+
+// UseClock defines the Clock used to wait between retries. It defaults to RealClock.
+func (r *ReconnectingEthClient) UseClock(clock Clock) {
+	r.clock = clockOrDefault(clock)
+}
+
+// RPC returns the current underlying RPC client.
+func (r *ReconnectingEthClient) RPC() *rpc.Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.rpc
+}
+
+// redial replaces the stale RPC client with a new connection to the endpoint. The dial happens outside the
+// lock and is bounded by dialTimeout so that callers of RPC are not blocked while the node is unreachable. If
+// another caller has already replaced the stale client, the current client is returned instead.
+func (r *ReconnectingEthClient) redial(stale *rpc.Client) (*rpc.Client, error) {
+	if c := r.RPC(); c != stale {
+		return c, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.dialTimeout)
+	defer cancel()
+	c, err := rpc.DialContext(ctx, r.url)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rpc != stale {
+		c.Close()
+		return r.rpc, nil
+	}
+	stale.Close()
+	r.rpc = c
+	return c, nil
+}
+
+func (r *ReconnectingEthClient) do(call func(c *rpc.Client) error) error {
+	c := r.RPC()
+	err := call(c)
+
+	delay := r.backoff
+	for i := 0; i < r.maxRetries && isConnectionError(err); i++ {
+		if delay > maxReconnectBackoff {
+			delay = maxReconnectBackoff
+		}
+		<-r.clock.After(delay)
+		delay *= 2
+
+		// Keep the stale client on a failed dial so that the next attempt dials again instead of reusing it.
+		nc, dialErr := r.redial(c)
+		if dialErr != nil {
+			err = dialErr
+			continue
+		}
+		c = nc
+		err = call(c)
+	}
+	return err
+}
+
+// GetUserOpReceiptWithReconnect returns an implementation of GetUserOpReceiptFunc that relies on a
+// ReconnectingEthClient to fetch a UserOperationReceipt.
+func GetUserOpReceiptWithReconnect(r *ReconnectingEthClient) GetUserOpReceiptFunc {
+	mustHaveReconnectingEthClient("GetUserOpReceiptWithReconnect", r)
+
+	return func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
+		var receipt *filter.UserOperationReceipt
+		err := r.do(func(c *rpc.Client) error {
+			var err error
+			receipt, err = filter.GetUserOperationReceipt(ethclient.NewClient(c), hash, ep, blkRange)
+			return err
+		})
+		return receipt, err
+	}
+}
+
+// GetGasEstimateWithReconnect returns an implementation of GetGasEstimateFunc that relies on a
+// ReconnectingEthClient to run the gas estimation.
+func GetGasEstimateWithReconnect(
+	r *ReconnectingEthClient,
+	ov *gas.Overhead,
+	chain *big.Int,
+	maxGasLimit *big.Int,
+	tracer string,
+) GetGasEstimateFunc {
+	mustHaveReconnectingEthClient("GetGasEstimateWithReconnect", r)
+	mustHaveEstimateParams("GetGasEstimateWithReconnect", r.RPC(), ov, chain, maxGasLimit)
+
+	return func(
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (verificationGas uint64, callGas uint64, err error) {
+		err = r.do(func(c *rpc.Client) error {
+			var err error
+			fn := GetGasEstimateWithEthClient(c, ov, chain, maxGasLimit, tracer)
+			verificationGas, callGas, err = fn(ep, op, sos)
+			return err
+		})
+		return verificationGas, callGas, err
+	}
+}
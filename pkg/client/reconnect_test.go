@@ -0,0 +1,241 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// flakyServer returns a server that drops the connection for the first drops requests and otherwise
+// responds with a JSON-RPC error.
+func flakyServer(drops int32, hits *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= drops {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				panic(err)
+			}
+			conn.Close()
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"execution reverted"}}`))
+	}))
+}
+
+func blockNumberCall(c *rpc.Client) error {
+	var bn hexutil.Uint64
+	return c.Call(&bn, "eth_blockNumber")
+}
+
+// TestReconnectingEthClientRedial verifies that a connection-level error causes the endpoint to be re-dialed
+// and the call to be retried.
+func TestReconnectingEthClientRedial(t *testing.T) {
+	hits := atomic.Int32{}
+	srv := flakyServer(2, &hits)
+	defer srv.Close()
+
+	r, err := NewReconnectingEthClient(srv.URL, 3, time.Millisecond)
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+	before := r.RPC()
+
+	if err := r.do(blockNumberCall); err == nil || isConnectionError(err) {
+		t.Fatalf("got %v, want rpc error", err)
+	} else if n := hits.Load(); n != 3 {
+		t.Fatalf("got %d hits, want 3", n)
+	} else if r.RPC() == before {
+		t.Fatal("got same rpc client, want redialed client")
+	}
+}
+
+// TestReconnectingEthClientMaxRetries verifies that the connection error is returned once retries are
+// exhausted and that the delay between retries doubles each time.
+func TestReconnectingEthClientMaxRetries(t *testing.T) {
+	hits := atomic.Int32{}
+	srv := flakyServer(10, &hits)
+	defer srv.Close()
+
+	r, err := NewReconnectingEthClient(srv.URL, 3, time.Second)
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+	clock := newFakeClock()
+	r.UseClock(clock)
+
+	if err := r.do(blockNumberCall); !isConnectionError(err) {
+		t.Fatalf("got %v, want connection error", err)
+	} else if n := hits.Load(); n != 4 {
+		t.Fatalf("got %d hits, want 4", n)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if !reflect.DeepEqual(clock.waited, want) {
+		t.Fatalf("got delays %v, want %v", clock.waited, want)
+	}
+}
+
+// TestReconnectingEthClientMaxBackoff verifies that the delay between retries is capped.
+func TestReconnectingEthClientMaxBackoff(t *testing.T) {
+	hits := atomic.Int32{}
+	srv := flakyServer(10, &hits)
+	defer srv.Close()
+
+	r, err := NewReconnectingEthClient(srv.URL, 4, 20*time.Second)
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+	clock := newFakeClock()
+	r.UseClock(clock)
+
+	_ = r.do(blockNumberCall)
+	want := []time.Duration{20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	if !reflect.DeepEqual(clock.waited, want) {
+		t.Fatalf("got delays %v, want %v", clock.waited, want)
+	}
+}
+
+// TestReconnectingEthClientNoRetry verifies that errors returned by the node are not retried.
+func TestReconnectingEthClientNoRetry(t *testing.T) {
+	hits := atomic.Int32{}
+	srv := flakyServer(0, &hits)
+	defer srv.Close()
+
+	r, err := NewReconnectingEthClient(srv.URL, 3, time.Millisecond)
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+	before := r.RPC()
+
+	if err := r.do(blockNumberCall); err == nil {
+		t.Fatal("got nil, want err")
+	} else if n := hits.Load(); n != 1 {
+		t.Fatalf("got %d hits, want 1", n)
+	} else if r.RPC() != before {
+		t.Fatal("got redialed client, want same rpc client")
+	}
+}
+
+// wsServer starts a websocket JSON-RPC server on addr and returns a func to stop it. The server and all open
+// connections are closed when it is stopped.
+func wsServer(t *testing.T, addr string) (string, func()) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcSrv := rpc.NewServer()
+	httpSrv := &http.Server{Handler: rpcSrv.WebsocketHandler(nil)}
+	go func() { _ = httpSrv.Serve(ln) }()
+
+	return ln.Addr().String(), func() {
+		rpcSrv.Stop()
+		_ = httpSrv.Close()
+	}
+}
+
+// TestReconnectingEthClientDialError verifies that a failed re-dial does not cause the next retry to reuse
+// the broken client.
+func TestReconnectingEthClientDialError(t *testing.T) {
+	addr, stop := wsServer(t, "127.0.0.1:0")
+	r, err := NewReconnectingEthClient("ws://"+addr, 2, time.Second)
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+	before := r.RPC()
+	stop()
+
+	// Bring the node back after the first failed re-dial.
+	clock := newFakeClock()
+	clock.onAfter = func(n int) {
+		if n == 2 {
+			_, stop = wsServer(t, addr)
+		}
+	}
+	r.UseClock(clock)
+	defer func() { stop() }()
+
+	if err := r.do(blockNumberCall); isConnectionError(err) {
+		t.Fatalf("got %v, want non-connection error", err)
+	} else if r.RPC() == before {
+		t.Fatal("got broken rpc client, want redialed client")
+	}
+}
+
+// TestNewReconnectingEthClientInvalid verifies that invalid retry params return an error.
+func TestNewReconnectingEthClientInvalid(t *testing.T) {
+	if _, err := NewReconnectingEthClient("http://localhost:8545", -1, time.Second); err == nil {
+		t.Fatal("got nil, want err for negative maxRetries")
+	}
+	if _, err := NewReconnectingEthClient("http://localhost:8545", 3, 0); err == nil {
+		t.Fatal("got nil, want err for zero backoff")
+	}
+}
+
+// TestReconnectingEthClientSlowDial verifies that a dial to an unresponsive node does not block callers of
+// RPC and that it gives up after the dial timeout.
+func TestReconnectingEthClientSlowDial(t *testing.T) {
+	// Accept connections but never complete the websocket handshake.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	hits := atomic.Int32{}
+	srv := flakyServer(0, &hits)
+	defer srv.Close()
+	stale, err := rpc.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &ReconnectingEthClient{
+		url:         "ws://" + ln.Addr().String(),
+		backoff:     time.Second,
+		dialTimeout: 200 * time.Millisecond,
+		clock:       RealClock(),
+		rpc:         stale,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.redial(stale)
+		done <- err
+	}()
+
+	got := make(chan *rpc.Client, 1)
+	go func() { got <- r.RPC() }()
+	select {
+	case c := <-got:
+		if c != stale {
+			t.Fatal("got new rpc client, want stale client while dialing")
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("got blocked RPC call, want stale client while dialing")
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("got nil, want dial timeout err")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("got no return from redial, want dial timeout")
+	}
+}
@@ -1,18 +1,54 @@
 package client
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"math/big"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+	bundlererrors "github.com/stackup-wallet/stackup-bundler/pkg/errors"
 	"github.com/stackup-wallet/stackup-bundler/pkg/fees"
 	"github.com/stackup-wallet/stackup-bundler/pkg/gas"
 	"github.com/stackup-wallet/stackup-bundler/pkg/state"
 	"github.com/stackup-wallet/stackup-bundler/pkg/userop"
 )
 
+// mustHaveEthClient panics if a constructor is given a nil eth client. This surfaces a misconfiguration at
+// startup instead of as a nil pointer dereference on the first call.
+func mustHaveEthClient(constructor string, eth *ethclient.Client) {
+	if eth == nil {
+		panic(fmt.Sprintf("client: %s requires a non-nil eth client", constructor))
+	}
+}
+
+func mustHaveEstimateParams(
+	constructor string,
+	rpc *rpc.Client,
+	ov *gas.Overhead,
+	chain *big.Int,
+	maxGasLimit *big.Int,
+) {
+	switch {
+	case rpc == nil:
+		panic(fmt.Sprintf("client: %s requires a non-nil rpc client", constructor))
+	case ov == nil:
+		panic(fmt.Sprintf("client: %s requires a non-nil gas overhead", constructor))
+	case chain == nil:
+		panic(fmt.Sprintf("client: %s requires a non-nil chain ID", constructor))
+	case maxGasLimit == nil:
+		panic(fmt.Sprintf("client: %s requires a non-nil max gas limit", constructor))
+	}
+}
+
 // GetUserOpReceiptFunc is a general interface for fetching a UserOperationReceipt given a userOpHash,
 // EntryPoint address, and block range.
 type GetUserOpReceiptFunc = func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error)
@@ -26,11 +62,43 @@ func getUserOpReceiptNoop() GetUserOpReceiptFunc {
 // GetUserOpReceiptWithEthClient returns an implementation of GetUserOpReceiptFunc that relies on an eth
 // client to fetch a UserOperationReceipt.
 func GetUserOpReceiptWithEthClient(eth *ethclient.Client) GetUserOpReceiptFunc {
+	mustHaveEthClient("GetUserOpReceiptWithEthClient", eth)
+
 	return func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
 		return filter.GetUserOperationReceipt(eth, hash, ep, blkRange)
 	}
 }
 
+// GetUserOpReceiptWithEthClientPaginated returns an implementation of GetUserOpReceiptFunc that relies on an
+// eth client to fetch a UserOperationReceipt. The block range is queried in chunks of at most maxChunk blocks
+// to support nodes that restrict the range of eth_getLogs.
+func GetUserOpReceiptWithEthClientPaginated(eth *ethclient.Client, maxChunk uint64) GetUserOpReceiptFunc {
+	mustHaveEthClient("GetUserOpReceiptWithEthClientPaginated", eth)
+	if maxChunk == 0 {
+		panic("client: GetUserOpReceiptWithEthClientPaginated requires maxChunk greater than 0")
+	}
+
+	return func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
+		return filter.GetUserOperationReceiptPaginated(eth, hash, ep, blkRange, maxChunk)
+	}
+}
+
+// GetUserOpReceiptWithDefaultRange wraps an implementation of GetUserOpReceiptFunc so that a blkRange of 0
+// is replaced with defaultBlkRange. A larger range can find older receipts but is more likely to exceed the
+// eth_getLogs limits of an RPC provider. An error is returned if the range is still 0 after applying the
+// default.
+func GetUserOpReceiptWithDefaultRange(fn GetUserOpReceiptFunc, defaultBlkRange uint64) GetUserOpReceiptFunc {
+	return func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
+		if blkRange == 0 {
+			blkRange = defaultBlkRange
+		}
+		if blkRange == 0 {
+			return nil, errors.New("receipt: block range must be greater than 0")
+		}
+		return fn(hash, ep, blkRange)
+	}
+}
+
 // GetGasPricesFunc is a general interface for fetching values for maxFeePerGas and maxPriorityFeePerGas.
 type GetGasPricesFunc = func() (*fees.GasPrices, error)
 
@@ -44,13 +112,98 @@ func getGasPricesNoop() GetGasPricesFunc {
 }
 
 // GetGasPricesWithEthClient returns an implementation of GetGasPricesFunc that relies on an eth client to
-// fetch values for maxFeePerGas and maxPriorityFeePerGas.
+// fetch values for maxFeePerGas and maxPriorityFeePerGas. If the chain does not support EIP-1559, both values
+// are set to the legacy gas price and IsLegacy is true.
 func GetGasPricesWithEthClient(eth *ethclient.Client) GetGasPricesFunc {
+	mustHaveEthClient("GetGasPricesWithEthClient", eth)
+
 	return func() (*fees.GasPrices, error) {
 		return fees.NewGasPrices(eth)
 	}
 }
 
+// GetGasPricesWithFeeHistory returns an implementation of GetGasPricesFunc that relies on eth_feeHistory to
+// derive maxPriorityFeePerGas from the given reward percentile over the last n blocks. If fee history is not
+// available it will fallback to the same behavior as GetGasPricesWithEthClient.
+func GetGasPricesWithFeeHistory(
+	eth *ethclient.Client,
+	blocks uint64,
+	percentile float64,
+) (GetGasPricesFunc, error) {
+	mustHaveEthClient("GetGasPricesWithFeeHistory", eth)
+	if blocks == 0 {
+		return nil, errors.New("gasPrices: fee history blocks must be greater than 0")
+	}
+	if !(percentile >= 0 && percentile <= 100) {
+		return nil, fmt.Errorf("gasPrices: percentile must be between 0 and 100, got %v", percentile)
+	}
+
+	return func() (*fees.GasPrices, error) {
+		return fees.NewGasPricesFromFeeHistory(eth, blocks, percentile)
+	}, nil
+}
+
+const (
+	gasPriceMultiplierBpsDenominator int64 = 10000
+	minGasPriceMultiplierBps         int64 = 10000
+	maxGasPriceMultiplierBps         int64 = 100000
+)
+
+func withGasPriceBounds(fn GetGasPricesFunc, floor *big.Int, multiplierBps int64) GetGasPricesFunc {
+	return func() (*fees.GasPrices, error) {
+		gp, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		bps := big.NewInt(multiplierBps)
+		denom := big.NewInt(gasPriceMultiplierBpsDenominator)
+		mf := big.NewInt(0).Div(big.NewInt(0).Mul(gp.MaxFeePerGas, bps), denom)
+		if mf.Cmp(floor) < 0 {
+			mf = big.NewInt(0).Set(floor)
+		}
+		mpf := big.NewInt(0).Div(big.NewInt(0).Mul(gp.MaxPriorityFeePerGas, bps), denom)
+		if mpf.Cmp(floor) < 0 {
+			mpf = big.NewInt(0).Set(floor)
+		}
+		if mpf.Cmp(mf) > 0 {
+			mpf = big.NewInt(0).Set(mf)
+		}
+
+		return &fees.GasPrices{
+			MaxFeePerGas:         mf,
+			MaxPriorityFeePerGas: mpf,
+			IsLegacy:             gp.IsLegacy,
+		}, nil
+	}
+}
+
+// GetGasPricesWithBounds returns an implementation of GetGasPricesFunc that relies on an eth client to fetch
+// values for maxFeePerGas and maxPriorityFeePerGas. Both values are scaled by multiplierBps (where 10000 is
+// 1x) and will never be set lower than floor. maxPriorityFeePerGas is capped at maxFeePerGas.
+func GetGasPricesWithBounds(
+	eth *ethclient.Client,
+	floor *big.Int,
+	multiplierBps int64,
+) (GetGasPricesFunc, error) {
+	mustHaveEthClient("GetGasPricesWithBounds", eth)
+	if floor == nil {
+		floor = big.NewInt(0)
+	} else if floor.Sign() < 0 {
+		return nil, fmt.Errorf("gasPrices: floor must not be negative, got %s", floor)
+	}
+	if multiplierBps < minGasPriceMultiplierBps || multiplierBps > maxGasPriceMultiplierBps {
+		return nil, fmt.Errorf(
+			"gasPrices: multiplierBps must be between %d and %d, got %d",
+			minGasPriceMultiplierBps,
+			maxGasPriceMultiplierBps,
+			multiplierBps,
+		)
+	}
+
+	return withGasPriceBounds(GetGasPricesWithEthClient(eth), floor, multiplierBps), nil
+}
+
 // GetGasEstimateFunc is a general interface for fetching an estimate for verificationGasLimit and
 // callGasLimit given a userOp and EntryPoint address.
 type GetGasEstimateFunc = func(
@@ -69,16 +222,28 @@ func getGasEstimateNoop() GetGasEstimateFunc {
 	}
 }
 
-// GetGasEstimateWithEthClient returns an implementation of GetGasEstimateFunc that relies on an eth client to
-// fetch an estimate for verificationGasLimit and callGasLimit.
-func GetGasEstimateWithEthClient(
+// GetGasEstimateFuncCtx is the context-aware equivalent of GetGasEstimateFunc. Cancelling the context will
+// abort any in-flight simulations used to derive the estimate.
+type GetGasEstimateFuncCtx = func(
+	ctx context.Context,
+	ep common.Address,
+	op *userop.UserOperation,
+	sos state.OverrideSet,
+) (verificationGas uint64, callGas uint64, err error)
+
+// GetGasEstimateWithEthClientCtx returns an implementation of GetGasEstimateFuncCtx that relies on an eth
+// client to fetch an estimate for verificationGasLimit and callGasLimit.
+func GetGasEstimateWithEthClientCtx(
 	rpc *rpc.Client,
 	ov *gas.Overhead,
 	chain *big.Int,
 	maxGasLimit *big.Int,
 	tracer string,
-) GetGasEstimateFunc {
+) GetGasEstimateFuncCtx {
+	mustHaveEstimateParams("GetGasEstimateWithEthClientCtx", rpc, ov, chain, maxGasLimit)
+
 	return func(
+		ctx context.Context,
 		ep common.Address,
 		op *userop.UserOperation,
 		sos state.OverrideSet,
@@ -92,10 +257,130 @@ func GetGasEstimateWithEthClient(
 			ChainID:     chain,
 			MaxGasLimit: maxGasLimit,
 			Tracer:      tracer,
+			Ctx:         ctx,
 		})
 	}
 }
 
+// GetGasEstimateWithEthClient returns an implementation of GetGasEstimateFunc that relies on an eth client to
+// fetch an estimate for verificationGasLimit and callGasLimit.
+func GetGasEstimateWithEthClient(
+	rpc *rpc.Client,
+	ov *gas.Overhead,
+	chain *big.Int,
+	maxGasLimit *big.Int,
+	tracer string,
+) GetGasEstimateFunc {
+	fn := GetGasEstimateWithEthClientCtx(rpc, ov, chain, maxGasLimit, tracer)
+
+	return func(
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (verificationGas uint64, callGas uint64, err error) {
+		return fn(context.Background(), ep, op, sos)
+	}
+}
+
+var aaErrorRegex = regexp.MustCompile(`\bAA\d\d `)
+
+// isRevertError returns true if the error was caused by the userOp reverting during simulation. These errors
+// are deterministic and expected to be the same on every node.
+func isRevertError(err error) bool {
+	var bundlerErr *bundlererrors.RPCError
+	if errors.As(err, &bundlerErr) {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "execution reverted") ||
+		strings.Contains(msg, "FailedOp") ||
+		aaErrorRegex.MatchString(msg)
+}
+
+var connectionErrorMsgs = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"unexpected EOF",
+}
+
+// isConnectionError returns true if the error was caused by the connection to the node rather than the node
+// itself. Websocket errors do not always wrap the underlying net.Error or io.ErrUnexpectedEOF so they are
+// also matched by message.
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, rpc.ErrClientQuit) {
+		return true
+	}
+
+	msg := err.Error()
+	for _, m := range connectionErrorMsgs {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransientRPCError returns true if the error was caused by the node or the connection to it rather than
+// the request itself. This includes JSON-RPC errors reported by the node, such as a tracer timeout, as long
+// as they are not a revert. These errors are safe to retry against another node.
+func isTransientRPCError(err error) bool {
+	if errors.Is(err, context.Canceled) || isRevertError(err) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError ||
+			httpErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) ||
+		isConnectionError(err) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// GetGasEstimateWithEthClients returns an implementation of GetGasEstimateFunc that tries each RPC client in
+// the given order until one of them succeeds. Only transient node errors will cause a fallback to the next
+// client. A revert during estimation is returned immediately since it is expected to be the same on every
+// node. The error from the last client is returned if all of them fail.
+func GetGasEstimateWithEthClients(
+	rpcs []*rpc.Client,
+	ov *gas.Overhead,
+	chain *big.Int,
+	maxGasLimit *big.Int,
+	tracer string,
+) GetGasEstimateFunc {
+	if len(rpcs) == 0 {
+		panic("client: GetGasEstimateWithEthClients requires at least one rpc client")
+	}
+	fns := []GetGasEstimateFunc{}
+	for _, c := range rpcs {
+		mustHaveEstimateParams("GetGasEstimateWithEthClients", c, ov, chain, maxGasLimit)
+		fns = append(fns, GetGasEstimateWithEthClient(c, ov, chain, maxGasLimit, tracer))
+	}
+
+	return func(
+		ep common.Address,
+		op *userop.UserOperation,
+		sos state.OverrideSet,
+	) (verificationGas uint64, callGas uint64, err error) {
+		for _, fn := range fns {
+			verificationGas, callGas, err = fn(ep, op, sos)
+			if err == nil || !isTransientRPCError(err) {
+				return verificationGas, callGas, err
+			}
+		}
+		return 0, 0, err
+	}
+}
+
 // GetUserOpByHashFunc is a general interface for fetching a UserOperation given a userOpHash, EntryPoint
 // address, chain ID, and block range.
 type GetUserOpByHashFunc func(hash string, ep common.Address, chain *big.Int, blkRange uint64) (*filter.HashLookupResult, error)
@@ -109,6 +394,8 @@ func getUserOpByHashNoop() GetUserOpByHashFunc {
 // GetUserOpByHashWithEthClient returns an implementation of GetUserOpByHashFunc that relies on an eth client
 // to fetch a UserOperation.
 func GetUserOpByHashWithEthClient(eth *ethclient.Client) GetUserOpByHashFunc {
+	mustHaveEthClient("GetUserOpByHashWithEthClient", eth)
+
 	return func(hash string, ep common.Address, chain *big.Int, blkRange uint64) (*filter.HashLookupResult, error) {
 		return filter.GetUserOperationByHash(eth, hash, ep, chain, blkRange)
 	}
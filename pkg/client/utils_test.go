@@ -0,0 +1,314 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stackup-wallet/stackup-bundler/internal/testutils"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/filter"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint/stake"
+	bundlererrors "github.com/stackup-wallet/stackup-bundler/pkg/errors"
+	"github.com/stackup-wallet/stackup-bundler/pkg/fees"
+	"github.com/stackup-wallet/stackup-bundler/pkg/gas"
+)
+
+func statusServer(code int, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		w.WriteHeader(code)
+	}))
+}
+
+// rpcErrorServer returns a server that responds to every request with the given JSON-RPC error.
+func rpcErrorServer(code int, message string, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			panic(err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"error":   map[string]any{"code": code, "message": message},
+		}); err != nil {
+			panic(err)
+		}
+	}))
+}
+
+// TestGetGasEstimateWithEthClientsFallback verifies that a transient error from one node will cause the
+// estimate to fallback to the next node and that a revert is returned without trying others.
+func TestGetGasEstimateWithEthClientsFallback(t *testing.T) {
+	hits1, hits2, hits3, hits4 := 0, 0, 0, 0
+	srv1 := statusServer(http.StatusBadGateway, &hits1)
+	defer srv1.Close()
+	srv2 := rpcErrorServer(-32000, "execution timeout", &hits2)
+	defer srv2.Close()
+	srv3 := rpcErrorServer(3, "execution reverted", &hits3)
+	defer srv3.Close()
+	srv4 := statusServer(http.StatusBadGateway, &hits4)
+	defer srv4.Close()
+
+	rpcs := []*rpc.Client{}
+	for _, srv := range []*httptest.Server{srv1, srv2, srv3, srv4} {
+		c, err := rpc.Dial(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rpcs = append(rpcs, c)
+	}
+
+	fn := GetGasEstimateWithEthClients(
+		rpcs,
+		gas.NewDefaultOverhead(),
+		testutils.ChainID,
+		big.NewInt(30000000),
+		"",
+	)
+	_, _, err := fn(testutils.ValidAddress1, testutils.MockValidInitUserOp(), nil)
+
+	if err == nil || !strings.Contains(err.Error(), "execution reverted") {
+		t.Fatalf("got %v, want execution reverted", err)
+	} else if hits1 != 1 || hits2 != 1 || hits3 != 1 || hits4 != 0 {
+		t.Fatalf("got hits %d, %d, %d, %d, want 1, 1, 1, 0", hits1, hits2, hits3, hits4)
+	}
+}
+
+type testRPCError struct {
+	code    int
+	message string
+}
+
+func (e testRPCError) Error() string  { return e.message }
+func (e testRPCError) ErrorCode() int { return e.code }
+
+// TestIsTransientRPCError verifies that node and connection level errors are considered transient and that
+// reverts are not.
+func TestIsTransientRPCError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{rpc.HTTPError{StatusCode: http.StatusServiceUnavailable}, true},
+		{rpc.HTTPError{StatusCode: http.StatusTooManyRequests}, true},
+		{rpc.HTTPError{StatusCode: http.StatusBadRequest}, false},
+		{testRPCError{-32000, "execution timeout"}, true},
+		{testRPCError{3, "execution reverted"}, false},
+		{bundlererrors.NewRPCError(bundlererrors.REJECTED_BY_EP_OR_ACCOUNT, "AA23 reverted", nil), false},
+		{errors.New("AA23 reverted"), false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+	}
+	for _, c := range cases {
+		if got := isTransientRPCError(c.err); got != c.want {
+			t.Fatalf("%v: got %t, want %t", c.err, got, c.want)
+		}
+	}
+}
+
+func staticGasPrices(mf int64, mpf int64) GetGasPricesFunc {
+	return func() (*fees.GasPrices, error) {
+		return &fees.GasPrices{
+			MaxFeePerGas:         big.NewInt(mf),
+			MaxPriorityFeePerGas: big.NewInt(mpf),
+		}, nil
+	}
+}
+
+// TestWithGasPriceBoundsMultiplier verifies that gas prices are scaled by the multiplier.
+func TestWithGasPriceBoundsMultiplier(t *testing.T) {
+	gp, err := withGasPriceBounds(staticGasPrices(100, 10), big.NewInt(0), 12000)()
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if gp.MaxFeePerGas.Cmp(big.NewInt(120)) != 0 {
+		t.Fatalf("got maxFeePerGas %s, want 120", gp.MaxFeePerGas)
+	} else if gp.MaxPriorityFeePerGas.Cmp(big.NewInt(12)) != 0 {
+		t.Fatalf("got maxPriorityFeePerGas %s, want 12", gp.MaxPriorityFeePerGas)
+	}
+}
+
+// TestWithGasPriceBoundsFloor verifies that gas prices are never lower than the floor and that
+// maxPriorityFeePerGas never exceeds maxFeePerGas.
+func TestWithGasPriceBoundsFloor(t *testing.T) {
+	gp, err := withGasPriceBounds(staticGasPrices(100, 10), big.NewInt(50), 10000)()
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if gp.MaxFeePerGas.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("got maxFeePerGas %s, want 100", gp.MaxFeePerGas)
+	} else if gp.MaxPriorityFeePerGas.Cmp(big.NewInt(50)) != 0 {
+		t.Fatalf("got maxPriorityFeePerGas %s, want 50", gp.MaxPriorityFeePerGas)
+	}
+
+	gp, err = withGasPriceBounds(staticGasPrices(10, 10), big.NewInt(50), 10000)()
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if gp.MaxFeePerGas.Cmp(big.NewInt(50)) != 0 {
+		t.Fatalf("got maxFeePerGas %s, want 50", gp.MaxFeePerGas)
+	} else if gp.MaxPriorityFeePerGas.Cmp(gp.MaxFeePerGas) > 0 {
+		t.Fatalf("got maxPriorityFeePerGas %s, want <= %s", gp.MaxPriorityFeePerGas, gp.MaxFeePerGas)
+	}
+}
+
+func mockEthClient(t *testing.T) *ethclient.Client {
+	srv := testutils.RpcMock(testutils.MethodMocks{})
+	t.Cleanup(srv.Close)
+	c, err := rpc.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ethclient.NewClient(c)
+}
+
+// TestGetGasPricesWithBoundsInvalid verifies that an invalid floor or multiplier returns an error.
+func TestGetGasPricesWithBoundsInvalid(t *testing.T) {
+	eth := mockEthClient(t)
+	if _, err := GetGasPricesWithBounds(eth, big.NewInt(-1), 10000); err == nil {
+		t.Fatal("got nil, want err for negative floor")
+	}
+	if _, err := GetGasPricesWithBounds(eth, nil, 9999); err == nil {
+		t.Fatal("got nil, want err for multiplier below 1x")
+	}
+	if _, err := GetGasPricesWithBounds(eth, nil, 100001); err == nil {
+		t.Fatal("got nil, want err for multiplier above 10x")
+	}
+}
+
+// TestGetGasEstimateWithEthClientCtxCancelled verifies that a cancelled context aborts gas estimation before
+// any request reaches the node.
+func TestGetGasEstimateWithEthClientCtxCancelled(t *testing.T) {
+	hits := 0
+	srv := statusServer(http.StatusOK, &hits)
+	defer srv.Close()
+	c, err := rpc.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fn := GetGasEstimateWithEthClientCtx(
+		c,
+		gas.NewDefaultOverhead(),
+		testutils.ChainID,
+		big.NewInt(30000000),
+		"",
+	)
+	if _, _, err := fn(ctx, testutils.ValidAddress1, testutils.MockValidInitUserOp(), nil); !errors.Is(
+		err,
+		context.Canceled,
+	) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	} else if hits != 0 {
+		t.Fatalf("got %d hits, want 0", hits)
+	} else if isTransientRPCError(err) {
+		t.Fatal("got transient, want non-transient for cancelled context")
+	}
+}
+
+// TestGetUserOpReceiptWithDefaultRange verifies that a block range of 0 is replaced with the default and that
+// an explicit block range is left unchanged.
+func TestGetUserOpReceiptWithDefaultRange(t *testing.T) {
+	var got uint64
+	fn := GetUserOpReceiptWithDefaultRange(
+		func(hash string, ep common.Address, blkRange uint64) (*filter.UserOperationReceipt, error) {
+			got = blkRange
+			return nil, nil
+		},
+		2000,
+	)
+
+	if _, err := fn(testutils.MockHash, testutils.ValidAddress1, 0); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if got != 2000 {
+		t.Fatalf("got block range %d, want 2000", got)
+	}
+	if _, err := fn(testutils.MockHash, testutils.ValidAddress1, 10); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if got != 10 {
+		t.Fatalf("got block range %d, want 10", got)
+	}
+}
+
+// TestGetUserOpReceiptWithDefaultRangeZero verifies that an error is returned if both the given and default
+// block ranges are 0.
+func TestGetUserOpReceiptWithDefaultRangeZero(t *testing.T) {
+	calls := 0
+	fn := GetUserOpReceiptWithDefaultRange(countingUserOpReceipt(&calls, nil), 0)
+	if _, err := fn(testutils.MockHash, testutils.ValidAddress1, 0); err == nil {
+		t.Fatal("got nil, want err")
+	} else if calls != 0 {
+		t.Fatalf("got %d calls, want 0", calls)
+	}
+}
+
+func expectPanic(t *testing.T, name string, fn func()) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("%s: got no panic, want panic", name)
+		}
+	}()
+	fn()
+}
+
+// TestConstructorsPanicOnNil verifies that constructors fail at construction time when given nil
+// dependencies.
+func TestConstructorsPanicOnNil(t *testing.T) {
+	expectPanic(t, "GetUserOpReceiptWithEthClient", func() { GetUserOpReceiptWithEthClient(nil) })
+	expectPanic(t, "GetUserOpReceiptWithEthClientPaginated", func() {
+		GetUserOpReceiptWithEthClientPaginated(nil, 1)
+	})
+	expectPanic(t, "GetGasPricesWithEthClient", func() { GetGasPricesWithEthClient(nil) })
+	expectPanic(t, "GetUserOpByHashWithEthClient", func() { GetUserOpByHashWithEthClient(nil) })
+	expectPanic(t, "GetGasPricesWithBounds", func() { _, _ = GetGasPricesWithBounds(nil, nil, 10000) })
+	expectPanic(t, "GetGasPricesWithFeeHistory", func() { _, _ = GetGasPricesWithFeeHistory(nil, 10, 50) })
+	expectPanic(t, "GetUserOpReceiptWithReconnect", func() { GetUserOpReceiptWithReconnect(nil) })
+	expectPanic(t, "GetGasEstimateWithReconnect", func() {
+		GetGasEstimateWithReconnect(nil, gas.NewDefaultOverhead(), testutils.ChainID, big.NewInt(1), "")
+	})
+	expectPanic(t, "GetStakeWithEthClient", func() { stake.GetStakeWithEthClient(nil) })
+	expectPanic(t, "GetGasEstimateWithEthClients", func() {
+		GetGasEstimateWithEthClients(nil, gas.NewDefaultOverhead(), testutils.ChainID, big.NewInt(1), "")
+	})
+	expectPanic(t, "GetGasEstimateWithEthClients nil entry", func() {
+		GetGasEstimateWithEthClients(
+			[]*rpc.Client{nil},
+			gas.NewDefaultOverhead(),
+			testutils.ChainID,
+			big.NewInt(1),
+			"",
+		)
+	})
+	expectPanic(t, "GetGasEstimateWithEthClient", func() {
+		GetGasEstimateWithEthClient(nil, gas.NewDefaultOverhead(), testutils.ChainID, big.NewInt(1), "")
+	})
+}
+
+// TestGetGasPricesWithFeeHistoryInvalid verifies that invalid fee history params return an error.
+func TestGetGasPricesWithFeeHistoryInvalid(t *testing.T) {
+	eth := mockEthClient(t)
+	if _, err := GetGasPricesWithFeeHistory(eth, 0, 50); err == nil {
+		t.Fatal("got nil, want err for 0 blocks")
+	}
+	if _, err := GetGasPricesWithFeeHistory(eth, 10, 101); err == nil {
+		t.Fatal("got nil, want err for percentile above 100")
+	}
+	if _, err := GetGasPricesWithFeeHistory(eth, 10, math.NaN()); err == nil {
+		t.Fatal("got nil, want err for NaN percentile")
+	}
+}
@@ -28,9 +28,16 @@ type SimulateInput struct {
 	// Optional params for simulateHandleOps
 	Target common.Address
 	Data   []byte
+
+	// Optional context for cancelling the underlying eth_call. Defaults to context.Background().
+	Ctx context.Context
 }
 
 func SimulateHandleOp(in *SimulateInput) (*reverts.ExecutionResultRevert, error) {
+	ctx := in.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	ep, err := entrypoint.NewEntrypoint(in.EntryPoint, ethclient.NewClient(in.Rpc))
 	if err != nil {
 		return nil, err
@@ -41,6 +48,7 @@ func SimulateHandleOp(in *SimulateInput) (*reverts.ExecutionResultRevert, error)
 	}
 	auth.GasLimit = math.MaxUint64
 	auth.NoSend = true
+	auth.Context = ctx
 	tx, err := ep.SimulateHandleOp(auth, entrypoint.UserOperation(*in.Op), in.Target, in.Data)
 	if err != nil {
 		return nil, err
@@ -51,7 +59,7 @@ func SimulateHandleOp(in *SimulateInput) (*reverts.ExecutionResultRevert, error)
 		To:   in.EntryPoint,
 		Data: tx.Data(),
 	}
-	err = in.Rpc.CallContext(context.Background(), nil, "eth_call", &req, "latest", in.Sos)
+	err = in.Rpc.CallContext(ctx, nil, "eth_call", &req, "latest", in.Sos)
 
 	sim, simErr := reverts.NewExecutionResult(err)
 	if simErr != nil {
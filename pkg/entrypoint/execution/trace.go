@@ -33,6 +33,9 @@ type TraceInput struct {
 	Target      common.Address
 	Data        []byte
 	TraceFeeCap *big.Int
+
+	// Optional context for cancelling the underlying debug_traceCall. Defaults to context.Background().
+	Ctx context.Context
 }
 
 type TraceOutput struct {
@@ -72,6 +75,10 @@ func parseUserOperationEvent(
 }
 
 func TraceSimulateHandleOp(in *TraceInput) (*TraceOutput, error) {
+	ctx := in.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	ep, err := entrypoint.NewEntrypoint(in.EntryPoint, ethclient.NewClient(in.Rpc))
 	if err != nil {
 		return nil, err
@@ -82,6 +89,7 @@ func TraceSimulateHandleOp(in *TraceInput) (*TraceOutput, error) {
 	}
 	auth.GasLimit = math.MaxUint64
 	auth.NoSend = true
+	auth.Context = ctx
 	mf := in.Op.MaxFeePerGas
 	if in.TraceFeeCap != nil {
 		mf = in.TraceFeeCap
@@ -107,7 +115,7 @@ func TraceSimulateHandleOp(in *TraceInput) (*TraceOutput, error) {
 		Tracer:         t,
 		StateOverrides: state.WithMaxBalanceOverride(common.HexToAddress("0x"), in.Sos),
 	}
-	if err := in.Rpc.CallContext(context.Background(), &res, "debug_traceCall", &req, "latest", &opts); err != nil {
+	if err := in.Rpc.CallContext(ctx, &res, "debug_traceCall", &req, "latest", &opts); err != nil {
 		return nil, err
 	}
 	outErr, err := errors.ParseHexToRpcDataError(res.Output)
@@ -10,16 +10,32 @@ import (
 	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint"
 )
 
-func filterUserOperationEvent(
+func filterUserOperationEventInRange(
 	eth *ethclient.Client,
 	userOpHash string,
 	entryPoint common.Address,
-	blkRange uint64,
+	start uint64,
+	end *uint64,
 ) (*entrypoint.EntrypointUserOperationEventIterator, error) {
 	ep, err := entrypoint.NewEntrypoint(entryPoint, eth)
 	if err != nil {
 		return nil, err
 	}
+
+	return ep.FilterUserOperationEvent(
+		&bind.FilterOpts{Start: start, End: end},
+		[][32]byte{common.HexToHash(userOpHash)},
+		[]common.Address{},
+		[]common.Address{},
+	)
+}
+
+func filterUserOperationEvent(
+	eth *ethclient.Client,
+	userOpHash string,
+	entryPoint common.Address,
+	blkRange uint64,
+) (*entrypoint.EntrypointUserOperationEventIterator, error) {
 	bn, err := eth.BlockNumber(context.Background())
 	if err != nil {
 		return nil, err
@@ -31,10 +47,5 @@ func filterUserOperationEvent(
 		startBlk = subBlkRange
 	}
 
-	return ep.FilterUserOperationEvent(
-		&bind.FilterOpts{Start: startBlk.Uint64()},
-		[][32]byte{common.HexToHash(userOpHash)},
-		[]common.Address{},
-		[]common.Address{},
-	)
+	return filterUserOperationEventInRange(eth, userOpHash, entryPoint, startBlk.Uint64(), nil)
 }
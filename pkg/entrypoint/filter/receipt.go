@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/stackup-wallet/stackup-bundler/pkg/entrypoint"
 )
 
 type parsedTransaction struct {
@@ -37,6 +39,55 @@ type UserOperationReceipt struct {
 	Logs          []*types.Log       `json:"logs"`
 }
 
+func newUserOperationReceipt(
+	eth *ethclient.Client,
+	it *entrypoint.EntrypointUserOperationEventIterator,
+) (*UserOperationReceipt, error) {
+	if !it.Next() {
+		return nil, nil
+	}
+
+	receipt, err := eth.TransactionReceipt(context.Background(), it.Event.Raw.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	tx, isPending, err := eth.TransactionByHash(context.Background(), it.Event.Raw.TxHash)
+	if err != nil {
+		return nil, err
+	} else if isPending {
+		return nil, nil
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	txnReceipt := &parsedTransaction{
+		BlockHash:         receipt.BlockHash,
+		BlockNumber:       hexutil.EncodeBig(receipt.BlockNumber),
+		From:              from,
+		CumulativeGasUsed: hexutil.EncodeBig(big.NewInt(0).SetUint64(receipt.CumulativeGasUsed)),
+		GasUsed:           hexutil.EncodeBig(big.NewInt(0).SetUint64(receipt.GasUsed)),
+		Logs:              receipt.Logs,
+		LogsBloom:         receipt.Bloom,
+		TransactionHash:   receipt.TxHash,
+		TransactionIndex:  hexutil.EncodeBig(big.NewInt(0).SetUint64(uint64(receipt.TransactionIndex))),
+		EffectiveGasPrice: hexutil.EncodeBig(tx.GasPrice()),
+	}
+	return &UserOperationReceipt{
+		UserOpHash:    it.Event.UserOpHash,
+		Sender:        it.Event.Sender,
+		Paymaster:     it.Event.Paymaster,
+		Nonce:         hexutil.EncodeBig(it.Event.Nonce),
+		Success:       it.Event.Success,
+		ActualGasCost: hexutil.EncodeBig(it.Event.ActualGasCost),
+		ActualGasUsed: hexutil.EncodeBig(it.Event.ActualGasUsed),
+		From:          from,
+		Receipt:       txnReceipt,
+		Logs:          []*types.Log{&it.Event.Raw},
+	}, nil
+}
+
 // GetUserOperationReceipt filters the EntryPoint contract for UserOperationEvents and returns a receipt for
 // both the UserOperation and accompanying transaction.
 func GetUserOperationReceipt(
@@ -55,47 +106,82 @@ func GetUserOperationReceipt(
 		return nil, err
 	}
 
-	if it.Next() {
-		receipt, err := eth.TransactionReceipt(context.Background(), it.Event.Raw.TxHash)
-		if err != nil {
-			return nil, err
+	return newUserOperationReceipt(eth, it)
+}
+
+var logRangeTooWideMsgs = []string{
+	"query returned more than",
+	"range too large",
+	"range too wide",
+	"exceed maximum block range",
+	"exceeds maximum block range",
+}
+
+// isLogRangeTooWide returns true if the node rejected an eth_getLogs query for covering too many blocks or
+// returning too many results. Other errors, such as rate limits, are not affected by the chunk size.
+func isLogRangeTooWide(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, m := range logRangeTooWideMsgs {
+		if strings.Contains(msg, m) {
+			return true
 		}
-		tx, isPending, err := eth.TransactionByHash(context.Background(), it.Event.Raw.TxHash)
-		if err != nil {
-			return nil, err
-		} else if isPending {
-			return nil, nil
+	}
+	return false
+}
+
+// GetUserOperationReceiptPaginated is the same as GetUserOperationReceipt except that it splits the block
+// range into chunks of at most maxChunk blocks. Chunks are queried starting from the latest block and the
+// lookup stops at the first match. If the node rejects a chunk for covering too many blocks or logs, the
+// chunk size is halved and the query is retried.
+func GetUserOperationReceiptPaginated(
+	eth *ethclient.Client,
+	userOpHash string,
+	entryPoint common.Address,
+	blkRange uint64,
+	maxChunk uint64,
+) (*UserOperationReceipt, error) {
+	if !IsValidUserOpHash(userOpHash) {
+		//lint:ignore ST1005 This needs to match the bundler test spec.
+		return nil, errors.New("Missing/invalid userOpHash")
+	}
+	if maxChunk == 0 {
+		return nil, errors.New("receipt: maxChunk must be greater than 0")
+	}
+
+	bn, err := eth.BlockNumber(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	startBlk := uint64(0)
+	if bn > blkRange {
+		startBlk = bn - blkRange
+	}
+
+	chunk := maxChunk
+	toBlk := bn
+	for {
+		fromBlk := startBlk
+		if toBlk-startBlk >= chunk {
+			fromBlk = toBlk - chunk + 1
 		}
-		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+
+		end := toBlk
+		it, err := filterUserOperationEventInRange(eth, userOpHash, entryPoint, fromBlk, &end)
 		if err != nil {
+			if isLogRangeTooWide(err) && chunk > 1 {
+				chunk = chunk / 2
+				continue
+			}
 			return nil, err
 		}
 
-		txnReceipt := &parsedTransaction{
-			BlockHash:         receipt.BlockHash,
-			BlockNumber:       hexutil.EncodeBig(receipt.BlockNumber),
-			From:              from,
-			CumulativeGasUsed: hexutil.EncodeBig(big.NewInt(0).SetUint64(receipt.CumulativeGasUsed)),
-			GasUsed:           hexutil.EncodeBig(big.NewInt(0).SetUint64(receipt.GasUsed)),
-			Logs:              receipt.Logs,
-			LogsBloom:         receipt.Bloom,
-			TransactionHash:   receipt.TxHash,
-			TransactionIndex:  hexutil.EncodeBig(big.NewInt(0).SetUint64(uint64(receipt.TransactionIndex))),
-			EffectiveGasPrice: hexutil.EncodeBig(tx.GasPrice()),
+		receipt, err := newUserOperationReceipt(eth, it)
+		if err != nil || receipt != nil {
+			return receipt, err
 		}
-		return &UserOperationReceipt{
-			UserOpHash:    it.Event.UserOpHash,
-			Sender:        it.Event.Sender,
-			Paymaster:     it.Event.Paymaster,
-			Nonce:         hexutil.EncodeBig(it.Event.Nonce),
-			Success:       it.Event.Success,
-			ActualGasCost: hexutil.EncodeBig(it.Event.ActualGasCost),
-			ActualGasUsed: hexutil.EncodeBig(it.Event.ActualGasUsed),
-			From:          from,
-			Receipt:       txnReceipt,
-			Logs:          []*types.Log{&it.Event.Raw},
-		}, nil
+		if fromBlk == startBlk {
+			return nil, nil
+		}
+		toBlk = fromBlk - 1
 	}
-
-	return nil, nil
 }
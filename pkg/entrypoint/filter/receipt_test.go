@@ -0,0 +1,152 @@
+package filter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stackup-wallet/stackup-bundler/internal/testutils"
+)
+
+type logRange struct {
+	from uint64
+	to   uint64
+}
+
+// cappedLogsMock returns a server that rejects eth_getLogs queries spanning more than maxRange blocks and
+// records the range of every accepted query.
+func cappedLogsMock(head uint64, maxRange uint64, ranges *[]logRange) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			panic(err)
+		}
+
+		res := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "eth_blockNumber":
+			res["result"] = hexutil.EncodeUint64(head)
+		case "eth_getLogs":
+			var q struct {
+				FromBlock hexutil.Uint64 `json:"fromBlock"`
+				ToBlock   hexutil.Uint64 `json:"toBlock"`
+			}
+			if err := json.Unmarshal(req.Params[0], &q); err != nil {
+				panic(err)
+			}
+			if uint64(q.ToBlock-q.FromBlock)+1 > maxRange {
+				res["error"] = map[string]any{
+					"code":    -32005,
+					"message": "query returned more than 10000 results",
+				}
+			} else {
+				*ranges = append(*ranges, logRange{uint64(q.FromBlock), uint64(q.ToBlock)})
+				res["result"] = []any{}
+			}
+		default:
+			res["error"] = map[string]any{"code": -32601, "message": "method not found"}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			panic(err)
+		}
+	}))
+}
+
+// TestGetUserOperationReceiptPaginated verifies that the block range is split into chunks that are adaptively
+// shrunk until the node accepts them and that every block in the range is covered exactly once.
+func TestGetUserOperationReceiptPaginated(t *testing.T) {
+	ranges := []logRange{}
+	srv := cappedLogsMock(100, 10, &ranges)
+	defer srv.Close()
+	c, _ := rpc.Dial(srv.URL)
+
+	receipt, err := GetUserOperationReceiptPaginated(
+		ethclient.NewClient(c),
+		testutils.MockHash,
+		testutils.ValidAddress1,
+		60,
+		50,
+	)
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if receipt != nil {
+		t.Fatalf("got %v, want nil receipt", receipt)
+	}
+
+	next := uint64(100)
+	for _, r := range ranges {
+		if r.to != next {
+			t.Fatalf("got range ending at %d, want %d", r.to, next)
+		} else if r.to-r.from+1 > 10 {
+			t.Fatalf("got range of %d blocks, want <= 10", r.to-r.from+1)
+		}
+		next = r.from - 1
+	}
+	if next != 39 {
+		t.Fatalf("got last range starting at %d, want 40", next+1)
+	}
+}
+
+// TestGetUserOperationReceiptPaginatedZeroChunk verifies that a maxChunk of 0 returns an error.
+func TestGetUserOperationReceiptPaginatedZeroChunk(t *testing.T) {
+	if _, err := GetUserOperationReceiptPaginated(
+		nil,
+		testutils.MockHash,
+		testutils.ValidAddress1,
+		60,
+		0,
+	); err == nil {
+		t.Fatal("got nil, want err")
+	}
+}
+
+// TestGetUserOperationReceiptPaginatedRateLimited verifies that a rate limit error is returned as is instead
+// of shrinking the chunk size.
+func TestGetUserOperationReceiptPaginatedRateLimited(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			panic(err)
+		}
+
+		res := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		if req.Method == "eth_blockNumber" {
+			res["result"] = hexutil.EncodeUint64(100)
+		} else {
+			hits++
+			res["error"] = map[string]any{"code": -32005, "message": "limit exceeded"}
+		}
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			panic(err)
+		}
+	}))
+	defer srv.Close()
+	c, _ := rpc.Dial(srv.URL)
+
+	if _, err := GetUserOperationReceiptPaginated(
+		ethclient.NewClient(c),
+		testutils.MockHash,
+		testutils.ValidAddress1,
+		60,
+		50,
+	); err == nil || !strings.Contains(err.Error(), "limit exceeded") {
+		t.Fatalf("got %v, want limit exceeded", err)
+	} else if hits != 1 {
+		t.Fatalf("got %d eth_getLogs calls, want 1", hits)
+	}
+}
@@ -18,6 +18,10 @@ func GetStakeFuncNoop() GetStakeFunc {
 // GetStakeWithEthClient returns a GetStakeFunc that relies on an eth client to get stake info from the
 // EntryPoint.
 func GetStakeWithEthClient(eth *ethclient.Client) GetStakeFunc {
+	if eth == nil {
+		panic("stake: GetStakeWithEthClient requires a non-nil eth client")
+	}
+
 	return func(entryPoint, addr common.Address) (*entrypoint.IStakeManagerDepositInfo, error) {
 		if addr == common.HexToAddress("0x") {
 			return nil, nil
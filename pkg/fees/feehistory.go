@@ -0,0 +1,46 @@
+package fees
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// NewGasPricesFromFeeHistory returns an instance of GasPrices derived from eth_feeHistory. The priority fee
+// is the median across the last n blocks of the given reward percentile. The max fee is the priority fee
+// plus twice the basefee of the pending block. If fee history is not available or the chain does not
+// support EIP-1559, this will fallback to NewGasPrices.
+func NewGasPricesFromFeeHistory(
+	eth *ethclient.Client,
+	blocks uint64,
+	percentile float64,
+) (*GasPrices, error) {
+	fh, err := eth.FeeHistory(context.Background(), blocks, nil, []float64{percentile})
+	if err != nil || len(fh.BaseFee) == 0 {
+		return NewGasPrices(eth)
+	}
+	bf := fh.BaseFee[len(fh.BaseFee)-1]
+	if bf == nil || bf.Sign() == 0 {
+		return NewGasPrices(eth)
+	}
+
+	tips := []*big.Int{}
+	for _, r := range fh.Reward {
+		if len(r) > 0 && r[0] != nil {
+			tips = append(tips, r[0])
+		}
+	}
+	if len(tips) == 0 {
+		return NewGasPrices(eth)
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+	tip := tips[len(tips)/2]
+
+	return &GasPrices{
+		MaxFeePerGas:         big.NewInt(0).Add(tip, big.NewInt(0).Mul(bf, common.Big2)),
+		MaxPriorityFeePerGas: big.NewInt(0).Set(tip),
+	}, nil
+}
@@ -12,6 +12,10 @@ import (
 type GasPrices struct {
 	MaxFeePerGas         *big.Int
 	MaxPriorityFeePerGas *big.Int
+
+	// IsLegacy is true if the chain does not support EIP-1559. In this case both MaxFeePerGas and
+	// MaxPriorityFeePerGas are equal to the legacy gas price.
+	IsLegacy bool
 }
 
 // NewGasPrices returns an instance of GasPrices with the latest suggested fees derived from an Eth Client.
@@ -33,6 +37,7 @@ func NewGasPrices(eth *ethclient.Client) (*GasPrices, error) {
 		}
 		gp.MaxFeePerGas = sgp
 		gp.MaxPriorityFeePerGas = sgp
+		gp.IsLegacy = true
 	}
 
 	return &gp, nil
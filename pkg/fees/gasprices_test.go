@@ -0,0 +1,105 @@
+package fees
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stackup-wallet/stackup-bundler/internal/testutils"
+)
+
+// TestNewGasPricesDynamic verifies that NewGasPrices returns EIP-1559 fees when the latest block has a
+// basefee.
+func TestNewGasPricesDynamic(t *testing.T) {
+	blk := testutils.NewBlockMock()
+	blk["miner"] = testutils.ValidAddress1.Hex()
+	blk["baseFeePerGas"] = "0x2"
+	n := testutils.RpcMock(testutils.MethodMocks{
+		"eth_getBlockByNumber":     blk,
+		"eth_maxPriorityFeePerGas": "0x1",
+	})
+	defer n.Close()
+	r, _ := rpc.Dial(n.URL)
+
+	gp, err := NewGasPrices(ethclient.NewClient(r))
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if gp.IsLegacy {
+		t.Fatal("got legacy, want dynamic")
+	} else if gp.MaxFeePerGas.Cmp(big.NewInt(5)) != 0 {
+		t.Fatalf("got maxFeePerGas %s, want 5", gp.MaxFeePerGas)
+	} else if gp.MaxPriorityFeePerGas.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("got maxPriorityFeePerGas %s, want 1", gp.MaxPriorityFeePerGas)
+	}
+}
+
+// TestNewGasPricesLegacy verifies that NewGasPrices returns the legacy gas price when the latest block has
+// no basefee.
+func TestNewGasPricesLegacy(t *testing.T) {
+	blk := testutils.NewBlockMock()
+	blk["miner"] = testutils.ValidAddress1.Hex()
+	n := testutils.RpcMock(testutils.MethodMocks{
+		"eth_getBlockByNumber": blk,
+		"eth_gasPrice":         "0x3",
+	})
+	defer n.Close()
+	r, _ := rpc.Dial(n.URL)
+
+	gp, err := NewGasPrices(ethclient.NewClient(r))
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if !gp.IsLegacy {
+		t.Fatal("got dynamic, want legacy")
+	} else if gp.MaxFeePerGas.Cmp(big.NewInt(3)) != 0 {
+		t.Fatalf("got maxFeePerGas %s, want 3", gp.MaxFeePerGas)
+	} else if gp.MaxPriorityFeePerGas.Cmp(big.NewInt(3)) != 0 {
+		t.Fatalf("got maxPriorityFeePerGas %s, want 3", gp.MaxPriorityFeePerGas)
+	}
+}
+
+// TestNewGasPricesFromFeeHistory verifies that the priority fee is the median reward and that the max fee is
+// based on the basefee of the pending block.
+func TestNewGasPricesFromFeeHistory(t *testing.T) {
+	n := testutils.RpcMock(testutils.MethodMocks{
+		"eth_feeHistory": map[string]any{
+			"oldestBlock":   "0x1",
+			"reward":        [][]string{{"0x1"}, {"0x3"}, {"0x2"}},
+			"baseFeePerGas": []string{"0x2", "0x2", "0x2", "0x4"},
+			"gasUsedRatio":  []float64{0.5, 0.5, 0.5},
+		},
+	})
+	defer n.Close()
+	r, _ := rpc.Dial(n.URL)
+
+	gp, err := NewGasPricesFromFeeHistory(ethclient.NewClient(r), 3, 50)
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if gp.MaxFeePerGas.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("got maxFeePerGas %s, want 10", gp.MaxFeePerGas)
+	} else if gp.MaxPriorityFeePerGas.Cmp(big.NewInt(2)) != 0 {
+		t.Fatalf("got maxPriorityFeePerGas %s, want 2", gp.MaxPriorityFeePerGas)
+	}
+}
+
+// TestNewGasPricesFromFeeHistoryFallback verifies that NewGasPricesFromFeeHistory falls back to NewGasPrices
+// if eth_feeHistory is not available.
+func TestNewGasPricesFromFeeHistoryFallback(t *testing.T) {
+	blk := testutils.NewBlockMock()
+	blk["miner"] = testutils.ValidAddress1.Hex()
+	n := testutils.RpcMock(testutils.MethodMocks{
+		"eth_getBlockByNumber": blk,
+		"eth_gasPrice":         "0x3",
+	})
+	defer n.Close()
+	r, _ := rpc.Dial(n.URL)
+
+	gp, err := NewGasPricesFromFeeHistory(ethclient.NewClient(r), 3, 50)
+	if err != nil {
+		t.Fatalf("got %v, want nil", err)
+	} else if !gp.IsLegacy {
+		t.Fatal("got dynamic, want legacy")
+	} else if gp.MaxFeePerGas.Cmp(big.NewInt(3)) != 0 {
+		t.Fatalf("got maxFeePerGas %s, want 3", gp.MaxFeePerGas)
+	}
+}
@@ -1,6 +1,7 @@
 package gas
 
 import (
+	"context"
 	"math/big"
 	"strings"
 
@@ -52,6 +53,9 @@ type EstimateInput struct {
 	MaxGasLimit *big.Int
 	Tracer      string
 
+	// Optional context for cancelling simulations during estimation. Defaults to context.Background().
+	Ctx context.Context
+
 	attempts int64
 	lastVGL  int64
 }
@@ -70,6 +74,7 @@ func retryEstimateGas(err error, vgl int64, in *EstimateInput) (uint64, uint64,
 			ChainID:     in.ChainID,
 			MaxGasLimit: in.MaxGasLimit,
 			Tracer:      in.Tracer,
+			Ctx:         in.Ctx,
 			attempts:    in.attempts + 1,
 			lastVGL:     vgl,
 		})
@@ -120,6 +125,7 @@ func EstimateGas(in *EstimateInput) (verificationGas uint64, callGas uint64, err
 			Op:         simOp,
 			Sos:        sosCpy,
 			ChainID:    in.ChainID,
+			Ctx:        in.Ctx,
 		})
 		simErr = err
 		if err == nil {
@@ -164,6 +170,7 @@ func EstimateGas(in *EstimateInput) (verificationGas uint64, callGas uint64, err
 		ChainID:     in.ChainID,
 		TraceFeeCap: in.Op.MaxFeePerGas,
 		Tracer:      in.Tracer,
+		Ctx:         in.Ctx,
 	})
 	if err != nil {
 		return retryEstimateGas(err, f, in)
@@ -192,6 +199,7 @@ func EstimateGas(in *EstimateInput) (verificationGas uint64, callGas uint64, err
 		Sos:        sosCpy,
 		ChainID:    in.ChainID,
 		Tracer:     in.Tracer,
+		Ctx:        in.Ctx,
 	})
 	if err != nil {
 		// Execution is successful but one shot tracing has failed. Fallback to binary search with an
@@ -217,6 +225,7 @@ func EstimateGas(in *EstimateInput) (verificationGas uint64, callGas uint64, err
 					Sos:        sosCpy,
 					ChainID:    in.ChainID,
 					Tracer:     in.Tracer,
+					Ctx:        in.Ctx,
 				})
 				simErr = err
 				if err == nil {